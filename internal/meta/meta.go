@@ -0,0 +1,31 @@
+package meta
+
+import (
+	"github.com/Azure/aztfy/internal/config"
+)
+
+// Meta is the seam between the import pipeline (the Bubble Tea UI and the
+// command layer) and whatever backs it. MetaImpl backs it with live Azure and
+// Terraform; MockMeta backs it with synthesized, deterministic data so the
+// pipeline can be exercised without Azure credentials or a terraform binary.
+type Meta interface {
+	ResourceGroupName() string
+	Workspace() string
+	Init() error
+	ListResource() ImportList
+	CleanTFState(addr string)
+	Import(item *ImportItem)
+	ImportAll(l ImportList, parallelism int) error
+	GenerateCfg(l ImportList) error
+	ExportResourceMapping(l ImportList) error
+}
+
+// NewMeta selects and constructs the Meta implementation that should drive the
+// import pipeline: MockMeta when cfg.MockClient is set (wired from the
+// AZTFY_MOCK_CLIENT environment variable), and the real MetaImpl otherwise.
+func NewMeta(cfg config.Config) (Meta, error) {
+	if cfg.MockClient {
+		return newMockMeta(cfg)
+	}
+	return newMetaImpl(cfg)
+}
@@ -8,7 +8,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Azure/aztfy/internal/armtemplate"
 	"github.com/Azure/aztfy/internal/config"
@@ -21,10 +24,16 @@ import (
 	"github.com/hashicorp/terraform-exec/tfexec"
 )
 
-// The required terraform version that has the `terraform add` command.
+// The required terraform version that has the `terraform add` command, used
+// for the classic "terraform add" + "terraform import" per-resource flow.
 var minRequiredTFVersion = version.Must(version.NewSemver("v1.1.0-alpha20210630"))
 var maxRequiredTFVersion = version.Must(version.NewSemver("v1.1.0-alpha20211006"))
 
+// The required terraform version that has native `import {}` config blocks and
+// `terraform plan -generate-config-out`, used when config.Config.GenerateImportBlock is set.
+var importBlockMinRequiredTFVersion = version.Must(version.NewSemver("v1.5.0"))
+var importBlockMaxRequiredTFVersion = version.Must(version.NewSemver("v1.9.0"))
+
 type MetaImpl struct {
 	subscriptionId string
 	resourceGroup  string
@@ -32,7 +41,15 @@ type MetaImpl struct {
 	outdir         string
 	tf             *tfexec.Terraform
 	auth           *Authorizer
-	armTemplate    armtemplate.Template
+	lister         ResourceLister
+	// armTemplate is only populated when lister is able to produce one (currently
+	// only the armTemplateLister can). It is nil when resources were discovered via
+	// a Resource Graph query or a management group/multi-RG scope, in which case
+	// dependency resolution falls back to the implicit resource group dependency.
+	armTemplate *armtemplate.Template
+	// resourceIds is the full set of Azure resource IDs discovered by lister,
+	// including the resource group itself for the default (single-RG) scope.
+	resourceIds []string
 
 	// Key is azure resource id; Value is terraform resource addr.
 	// For azure resources not in this mapping, they are all initialized as to skip.
@@ -43,6 +60,29 @@ type MetaImpl struct {
 
 	backendType   string
 	backendConfig []string
+
+	// genImportBlock selects the TF 1.5+ native `import {}` block flow over the
+	// classic per-resource "terraform add" + "terraform import" flow.
+	genImportBlock bool
+
+	// modulePath, when non-empty, makes generateConfig emit the imported
+	// resources under <outdir>/<modulePath>/ as a reusable module, plus a root
+	// main.tf wiring a "module" block to it, rather than a flat main.tf.
+	modulePath string
+
+	// mode selects how outdir is treated: Fresh (the original behavior) wipes
+	// or rejects a non-empty outdir; Append merges onto whatever is already
+	// there; Remote populates outdir from a remote module source in Init.
+	mode config.Mode
+	// remoteModuleSource is the git/S3/HTTPS module address Init pulls into
+	// outdir via "terraform init -from-module=" when mode is config.ModeRemote.
+	remoteModuleSource string
+
+	// stateMu serializes CleanTFState's "terraform state rm" runs against
+	// meta.tf, so CleanTFState is safe to call from several goroutines at once.
+	// Other uses of meta.tf, and ImportAll's workers (which have their own
+	// tfexec handles), do not take it.
+	stateMu sync.Mutex
 }
 
 func newMetaImpl(cfg config.Config) (Meta, error) {
@@ -75,42 +115,82 @@ func newMetaImpl(cfg config.Config) (Meta, error) {
 	if !stat.IsDir() {
 		return nil, fmt.Errorf("the output path %q is not a directory", outdir)
 	}
-	dir, err := os.Open(outdir)
-	if err != nil {
-		return nil, err
-	}
-	_, err = dir.Readdirnames(1)
-	dir.Close()
-	if err != io.EOF {
-		if cfg.Overwrite {
-			if err := removeEverythingUnder(outdir); err != nil {
-				return nil, err
-			}
-		} else {
-			if cfg.BatchMode {
-				return nil, fmt.Errorf("the output directory %q is not empty", outdir)
-			}
-
-			// Interactive mode
-			fmt.Printf("The output directory is not empty - overwrite (Y/N)? ")
-			var ans string
-			fmt.Scanf("%s", &ans)
-			if !strings.EqualFold(ans, "y") {
-				return nil, fmt.Errorf("the output directory %q is not empty", outdir)
-			} else {
+	// Fresh is the only mode where a non-empty output directory is a problem:
+	// Append is explicitly there to layer onto existing config, and Remote
+	// populates outdir itself via "terraform init -from-module=" in Init.
+	// removeEverythingUnder is thus no longer the default escape hatch for a
+	// non-empty outdir - it only ever runs for Fresh, and only once the user
+	// (or --overwrite) has actually asked for it.
+	if cfg.Mode == config.ModeFresh {
+		dir, err := os.Open(outdir)
+		if err != nil {
+			return nil, err
+		}
+		_, err = dir.Readdirnames(1)
+		dir.Close()
+		if err != io.EOF {
+			if cfg.Overwrite {
 				if err := removeEverythingUnder(outdir); err != nil {
 					return nil, err
 				}
+			} else {
+				if cfg.BatchMode {
+					return nil, fmt.Errorf("the output directory %q is not empty", outdir)
+				}
+
+				// Interactive mode
+				fmt.Printf("The output directory is not empty - overwrite (Y/N)? ")
+				var ans string
+				fmt.Scanf("%s", &ans)
+				if !strings.EqualFold(ans, "y") {
+					return nil, fmt.Errorf("the output directory %q is not empty", outdir)
+				} else {
+					if err := removeEverythingUnder(outdir); err != nil {
+						return nil, err
+					}
+				}
 			}
 		}
 	}
 
+	// generateCfgViaImportBlock hands the whole config generation over to a
+	// single "terraform plan -generate-config-out=<outdir>/main.tf", which
+	// knows nothing about --module-path's module layout or Append/Remote's
+	// pre-existing main.tf (Append's own, or Remote's pulled-module one -
+	// either of which makes "-generate-config-out" fail since it refuses to
+	// write over an existing file). Rather than silently ignoring or breaking
+	// on the combination, reject it up front.
+	if cfg.GenerateImportBlock {
+		if cfg.ModulePath != "" {
+			return nil, fmt.Errorf("--generate-import-block is not compatible with --module-path")
+		}
+		if cfg.Mode == config.ModeAppend {
+			return nil, fmt.Errorf("--generate-import-block is not compatible with append mode")
+		}
+		if cfg.Mode == config.ModeRemote {
+			return nil, fmt.Errorf("--generate-import-block is not compatible with remote mode")
+		}
+	}
+
+	// generateConfigAsModule unconditionally overwrites <outdir>/main.tf with
+	// its "module" block wiring, which would destroy whatever pre-existing
+	// root config Append mode is there to preserve. Reject the combination
+	// rather than having --module-path silently clobber it.
+	if cfg.ModulePath != "" && cfg.Mode == config.ModeAppend {
+		return nil, fmt.Errorf("--module-path is not compatible with append mode")
+	}
+
 	// Authentication
 	auth, err := NewAuthorizer()
 	if err != nil {
 		return nil, fmt.Errorf("building authorizer: %w", err)
 	}
 
+	lister, err := newResourceLister(cfg, auth, auth.Config.SubscriptionID, cfg.ResourceGroupName)
+	if err != nil {
+		return nil, fmt.Errorf("building the resource lister: %w", err)
+	}
+
 	// Resource mapping file
 	m := ResourceMapping{}
 	if cfg.ResourceMappingFile != "" {
@@ -127,14 +207,19 @@ func newMetaImpl(cfg config.Config) (Meta, error) {
 	os.Setenv("AZURE_HTTP_USER_AGENT", "aztfy")
 
 	meta := &MetaImpl{
-		subscriptionId:  auth.Config.SubscriptionID,
-		resourceGroup:   cfg.ResourceGroupName,
-		rootdir:         rootdir,
-		outdir:          outdir,
-		auth:            auth,
-		resourceMapping: m,
-		backendType:     cfg.BackendType,
-		backendConfig:   cfg.BackendConfig,
+		subscriptionId:     auth.Config.SubscriptionID,
+		resourceGroup:      cfg.ResourceGroupName,
+		rootdir:            rootdir,
+		outdir:             outdir,
+		auth:               auth,
+		lister:             lister,
+		resourceMapping:    m,
+		backendType:        cfg.BackendType,
+		backendConfig:      cfg.BackendConfig,
+		genImportBlock:     cfg.GenerateImportBlock,
+		modulePath:         cfg.ModulePath,
+		mode:               cfg.Mode,
+		remoteModuleSource: cfg.RemoteModuleSource,
 	}
 
 	if pos := strings.LastIndex(cfg.ResourceNamePattern, "*"); pos != -1 {
@@ -162,7 +247,11 @@ func (meta *MetaImpl) Init() error {
 	if err := os.MkdirAll(tfDir, 0755); err != nil {
 		return fmt.Errorf("creating terraform cache dir %q: %w", tfDir, err)
 	}
-	execPath, err := FindTerraform(ctx, tfDir, minRequiredTFVersion, maxRequiredTFVersion)
+	reqMinTFVersion, reqMaxTFVersion := minRequiredTFVersion, maxRequiredTFVersion
+	if meta.genImportBlock {
+		reqMinTFVersion, reqMaxTFVersion = importBlockMinRequiredTFVersion, importBlockMaxRequiredTFVersion
+	}
+	execPath, err := FindTerraform(ctx, tfDir, reqMinTFVersion, reqMaxTFVersion)
 	if err != nil {
 		return fmt.Errorf("error finding a terraform exectuable: %w", err)
 	}
@@ -172,31 +261,53 @@ func (meta *MetaImpl) Init() error {
 	}
 	meta.tf = tf
 
+	if meta.mode == config.ModeRemote {
+		if err := meta.tf.Init(ctx, tfexec.FromModule(meta.remoteModuleSource)); err != nil {
+			return fmt.Errorf("initializing outdir from remote module %q: %w", meta.remoteModuleSource, err)
+		}
+	}
+
 	// Initialize the provider
 	if err := meta.initProvider(ctx); err != nil {
 		return err
 	}
 
-	// Export ARM template
-	if err := meta.exportArmTemplate(ctx); err != nil {
-		return err
+	// Discover the resources to import.
+	ids, tpl, err := meta.lister.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing resources: %w", err)
+	}
+	meta.resourceIds = ids
+	meta.armTemplate = tpl
+	if meta.resourceGroup != "" {
+		meta.resourceIds = append(meta.resourceIds, armtemplate.ResourceGroupId.ID(meta.subscriptionId, meta.resourceGroup))
 	}
 	return nil
 }
 
 func (meta MetaImpl) ListResource() ImportList {
-	var ids []string
-	for _, res := range meta.armTemplate.Resources {
-		ids = append(ids, res.ID(meta.subscriptionId, meta.resourceGroup))
+	ids := meta.resourceIds
+
+	// In Append mode, newly imported resources must not collide in name with
+	// whatever *.tf files are already in outdir.
+	var existingNames map[string]bool
+	if meta.mode == config.ModeAppend {
+		names, err := existingResourceNames(meta.outdir)
+		if err == nil {
+			existingNames = names
+		}
 	}
-	ids = append(ids, armtemplate.ResourceGroupId.ID(meta.subscriptionId, meta.resourceGroup))
 
 	l := make(ImportList, 0, len(ids))
 	for i, id := range ids {
+		name := fmt.Sprintf("%s%d%s", meta.resourceNamePrefix, i, meta.resourceNameSuffix)
+		if existingNames != nil {
+			name = uniqueResourceName(name, existingNames)
+		}
 		item := ImportItem{
 			ResourceID: id,
 			TFAddr: TFAddr{
-				Name: fmt.Sprintf("%s%d%s", meta.resourceNamePrefix, i, meta.resourceNameSuffix),
+				Name: name,
 			},
 		}
 
@@ -213,12 +324,65 @@ func (meta MetaImpl) ListResource() ImportList {
 	return l
 }
 
+// existingResourceNames returns the set of Terraform resource names already
+// declared in any *.tf file under dir, so Append mode can keep newly imported
+// resources from colliding with them.
+func existingResourceNames(dir string) (map[string]bool, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, path := range matches {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		f, diags := hclwrite.ParseConfig(b, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("parsing %s: %s", path, diags.Error())
+		}
+		for _, block := range f.Body().Blocks() {
+			if block.Type() != "resource" {
+				continue
+			}
+			if labels := block.Labels(); len(labels) == 2 {
+				names[labels[1]] = true
+			}
+		}
+	}
+	return names, nil
+}
+
+// uniqueResourceName returns name, or name suffixed with "_N" for the lowest N
+// that doesn't collide with an entry already in existing. existing is updated
+// with whatever name is returned, so repeated calls keep producing distinct names.
+func uniqueResourceName(name string, existing map[string]bool) string {
+	candidate := name
+	for i := 1; existing[candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	existing[candidate] = true
+	return candidate
+}
+
 func (meta *MetaImpl) CleanTFState(addr string) {
 	ctx := context.TODO()
+	meta.stateMu.Lock()
+	defer meta.stateMu.Unlock()
 	meta.tf.StateRm(ctx, addr)
 }
 
 func (meta MetaImpl) Import(item *ImportItem) {
+	if meta.genImportBlock {
+		// The actual import is deferred to the single "terraform plan -generate-config-out"
+		// run that GenerateCfg drives over the whole list, so there is nothing to do here
+		// beyond letting the progress UI advance.
+		item.Imported = true
+		return
+	}
+
 	ctx := context.TODO()
 
 	// Generate a temp Terraform config to include the empty template for each resource.
@@ -242,18 +406,207 @@ func (meta MetaImpl) Import(item *ImportItem) {
 	item.Imported = err == nil
 }
 
+// ImportAll fans the import of l out to parallelism workers, each with its own
+// scratch directory and *tfexec.Terraform handle, since "terraform add"/"terraform
+// import" write to files in the working directory and can't safely share one
+// across goroutines. All workers point at the same backend as meta.tf, so
+// imports still land in the shared state; state-lock contention between them
+// is handled by retrying after a fixed delay rather than serializing the workers.
+//
+// parallelism <= 0 defaults to runtime.NumCPU().
+func (meta *MetaImpl) ImportAll(l ImportList, parallelism int) error {
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+
+	items := make(chan *ImportItem)
+	var wg sync.WaitGroup
+	for id := 0; id < parallelism; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			worker, err := meta.newImportWorker(id)
+			if err != nil {
+				for item := range items {
+					item.ImportError = fmt.Errorf("starting import worker %d: %w", id, err)
+				}
+				return
+			}
+			defer worker.Close()
+			for item := range items {
+				worker.Import(item)
+			}
+		}(id)
+	}
+
+	for i := range l {
+		if l[i].Skip() {
+			continue
+		}
+		items <- &l[i]
+	}
+	close(items)
+	wg.Wait()
+
+	return nil
+}
+
+// importWorker is a self-contained Terraform working directory used by one
+// ImportAll goroutine. It shares the outer MetaImpl's backend/state, but gets
+// its own scratch directory and tfexec handle so concurrent imports don't race
+// on the same "terraform add"/"terraform import" working files.
+type importWorker struct {
+	meta *MetaImpl
+	dir  string
+	tf   *tfexec.Terraform
+	// stateOpt, when non-nil, pins the worker's "terraform import" at the
+	// same local state file meta.tf reads from, rather than whatever state
+	// its own scratch dir's "terraform init" would otherwise produce. See
+	// newImportWorker for why this only applies to the local backend.
+	stateOpt []tfexec.ImportOption
+}
+
+func (meta *MetaImpl) newImportWorker(id int) (*importWorker, error) {
+	dir := filepath.Join(meta.rootdir, "worker", fmt.Sprintf("%d", id))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating worker scratch dir %q: %w", dir, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "provider.tf"), []byte(meta.providerConfig()), 0644); err != nil {
+		return nil, fmt.Errorf("writing worker provider config: %w", err)
+	}
+
+	tf, err := tfexec.NewTerraform(dir, meta.tf.ExecPath())
+	if err != nil {
+		return nil, fmt.Errorf("initializing worker terraform: %w", err)
+	}
+
+	var opts []tfexec.InitOption
+	for _, opt := range meta.backendConfig {
+		opts = append(opts, tfexec.BackendConfig(opt))
+	}
+	if err := tf.Init(context.TODO(), opts...); err != nil {
+		return nil, fmt.Errorf("running terraform init in worker dir %q: %w", dir, err)
+	}
+
+	// With the default local backend, the "terraform init" above gives this
+	// worker a state file scoped to its own scratch dir - which Close below
+	// deletes, destroying whatever got imported there before GenerateCfg ever
+	// reads from meta.tf's state. Force every worker to read/write the exact
+	// same local state file meta.tf uses instead. Remote backends already
+	// share state via the backend itself, so this is only needed for local.
+	var stateOpt []tfexec.ImportOption
+	if meta.backendType == "local" {
+		stateOpt = append(stateOpt, tfexec.State(meta.localStateFile()))
+	}
+
+	return &importWorker{meta: meta, dir: dir, tf: tf, stateOpt: stateOpt}, nil
+}
+
+// localStateFile is the local-backend state file meta.tf itself reads from
+// and writes to. Import workers are pinned to it (see newImportWorker) so
+// that, with the default local backend, imports performed by any worker are
+// visible to the later GenerateCfg/stateToConfig pass run against meta.tf.
+func (meta MetaImpl) localStateFile() string {
+	return filepath.Join(meta.outdir, "terraform.tfstate")
+}
+
+// Close removes the worker's scratch directory. This is safe even under the
+// local backend: the imported state itself lives at meta.localStateFile(),
+// outside of dir, not in a state file scoped to the scratch dir.
+func (w *importWorker) Close() error {
+	return os.RemoveAll(w.dir)
+}
+
+// importLockRetries/importLockRetryDelay bound how long a worker will retry an
+// import that failed only because another worker held the state lock.
+const importLockRetries = 5
+
+const importLockRetryDelay = 2 * time.Second
+
+// Import mirrors MetaImpl.Import, but runs against the worker's own scratch
+// directory and retries on state-lock contention from sibling workers.
+func (w *importWorker) Import(item *ImportItem) {
+	if w.meta.genImportBlock {
+		// As in MetaImpl.Import, the actual import happens in GenerateCfg.
+		item.Imported = true
+		return
+	}
+
+	ctx := context.TODO()
+
+	cfgFile := filepath.Join(w.dir, "main.tf")
+	tpl, err := w.tf.Add(ctx, item.TFAddr.String())
+	if err != nil {
+		item.ImportError = fmt.Errorf("generating resource template for %s: %w", item.TFAddr, err)
+		return
+	}
+	tpl = w.meta.cleanupTerraformAdd(tpl)
+	if err := os.WriteFile(cfgFile, []byte(tpl), 0644); err != nil {
+		item.ImportError = fmt.Errorf("generating resource template file: %w", err)
+		return
+	}
+	defer os.Remove(cfgFile)
+
+	err = w.importWithLockRetry(ctx, item)
+	item.ImportError = err
+	item.Imported = err == nil
+}
+
+func (w *importWorker) importWithLockRetry(ctx context.Context, item *ImportItem) error {
+	opts := append([]tfexec.ImportOption{tfexec.Lock(true)}, w.stateOpt...)
+	var err error
+	for attempt := 0; attempt < importLockRetries; attempt++ {
+		err = w.tf.Import(ctx, item.TFAddr.String(), item.ResourceID, opts...)
+		if err == nil || !strings.Contains(err.Error(), "Error acquiring the state lock") {
+			return err
+		}
+		time.Sleep(importLockRetryDelay)
+	}
+	return err
+}
+
 func (meta MetaImpl) GenerateCfg(l ImportList) error {
+	if meta.genImportBlock {
+		return meta.generateCfgViaImportBlock(l)
+	}
+
 	ctx := context.TODO()
 
 	cfginfos, err := meta.stateToConfig(ctx, l)
 	if err != nil {
 		return fmt.Errorf("converting from state to configurations: %w", err)
 	}
-	cfginfos, err = meta.resolveDependency(cfginfos)
+	cfginfos, extDeps, err := meta.resolveDependency(cfginfos)
 	if err != nil {
 		return fmt.Errorf("resolving cross resource dependencies: %w", err)
 	}
-	return meta.generateConfig(cfginfos)
+	return meta.generateConfig(cfginfos, extDeps)
+}
+
+// generateCfgViaImportBlock writes one native `import {}` block per imported
+// resource to import.tf, then runs "terraform plan -generate-config-out" to
+// have Terraform itself produce the resource configuration. Unlike the classic
+// flow, the import.tf file is left in outdir: it's a reviewable, declarative
+// artifact users can commit to VCS and re-run idempotently.
+func (meta MetaImpl) generateCfgViaImportBlock(l ImportList) error {
+	ctx := context.TODO()
+
+	buf := bytes.NewBuffer([]byte{})
+	for _, item := range l.Imported() {
+		fmt.Fprintf(buf, "import {\n  to = %s\n  id = %q\n}\n\n", item.TFAddr, item.ResourceID)
+	}
+	importFile := filepath.Join(meta.outdir, "import.tf")
+	if err := os.WriteFile(importFile, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing the import blocks to %s: %w", importFile, err)
+	}
+
+	cfgFile := filepath.Join(meta.outdir, "main.tf")
+	if _, err := meta.tf.Plan(ctx, tfexec.GenerateConfigOut(cfgFile)); err != nil {
+		return fmt.Errorf(`running "terraform plan -generate-config-out": %w`, err)
+	}
+
+	return nil
 }
 
 func (meta MetaImpl) ExportResourceMapping(l ImportList) error {
@@ -293,11 +646,32 @@ provider "azurerm" {
 }
 
 func (meta *MetaImpl) initProvider(ctx context.Context) error {
-	cfgFile := filepath.Join(meta.outdir, "provider.tf")
+	// In Append mode outdir is an existing repo that may already declare its
+	// own backend/provider config, and in Remote mode the "terraform init
+	// -from-module=" run in Init above may have already populated outdir with
+	// the pulled module's own provider block. Writing ours on top would
+	// clobber Append's (the whole point of layering onto a real repo) or
+	// duplicate Remote's (which terraform validate/plan would then reject).
+	// Only write ours when outdir declares no provider config yet - but
+	// "terraform init" itself must still run unconditionally: Append has no
+	// earlier init call at all, so skipping this one would leave .terraform/
+	// (providers, lock file) never populated and every later tf.Add/tf.Import
+	// call failing.
+	skipWrite := false
+	if meta.mode == config.ModeAppend || meta.mode == config.ModeRemote {
+		has, err := hasProviderConfig(meta.outdir)
+		if err != nil {
+			return fmt.Errorf("checking for existing provider config in %q: %w", meta.outdir, err)
+		}
+		skipWrite = has
+	}
 
-	// Always use the latest provider version here, as this is a one shot tool, which should guarantees to work with the latest version.
-	if err := os.WriteFile(cfgFile, []byte(meta.providerConfig()), 0644); err != nil {
-		return fmt.Errorf("error creating provider config: %w", err)
+	if !skipWrite {
+		cfgFile := filepath.Join(meta.outdir, "provider.tf")
+		// Always use the latest provider version here, as this is a one shot tool, which should guarantees to work with the latest version.
+		if err := os.WriteFile(cfgFile, []byte(meta.providerConfig()), 0644); err != nil {
+			return fmt.Errorf("error creating provider config: %w", err)
+		}
 	}
 
 	var opts []tfexec.InitOption
@@ -311,25 +685,55 @@ func (meta *MetaImpl) initProvider(ctx context.Context) error {
 	return nil
 }
 
-func (meta *MetaImpl) exportArmTemplate(ctx context.Context) error {
-	client := meta.auth.NewResourceGroupClient()
+// hasProviderConfig reports whether any *.tf file already under dir declares
+// a "provider" block, so initProvider can tell whether writing its own
+// provider.tf would duplicate or clobber one that's already there.
+func hasProviderConfig(dir string) (bool, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return false, err
+	}
+	for _, path := range matches {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("reading %s: %w", path, err)
+		}
+		f, diags := hclwrite.ParseConfig(b, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return false, fmt.Errorf("parsing %s: %s", path, diags.Error())
+		}
+		for _, block := range f.Body().Blocks() {
+			if block.Type() == "provider" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// exportArmTemplate exports the ARM template of a single resource group. It is
+// used directly by armTemplateLister, and is kept as a free function (rather
+// than a MetaImpl method) since it only depends on the authorizer and the
+// resource group name.
+func exportArmTemplate(ctx context.Context, auth *Authorizer, resourceGroup string) (*armtemplate.Template, error) {
+	client := auth.NewResourceGroupClient()
 
 	exportOpt := "SkipAllParameterization"
-	future, err := client.ExportTemplate(ctx, meta.resourceGroup, resources.ExportTemplateRequest{
+	future, err := client.ExportTemplate(ctx, resourceGroup, resources.ExportTemplateRequest{
 		ResourcesProperty: &[]string{"*"},
 		Options:           &exportOpt,
 	})
 	if err != nil {
-		return fmt.Errorf("exporting arm template of resource group %s: %w", meta.resourceGroup, err)
+		return nil, fmt.Errorf("exporting arm template of resource group %s: %w", resourceGroup, err)
 	}
 
 	if err := future.WaitForCompletionRef(ctx, client.Client); err != nil {
-		return fmt.Errorf("waiting for exporting arm template of resource group %s: %w", meta.resourceGroup, err)
+		return nil, fmt.Errorf("waiting for exporting arm template of resource group %s: %w", resourceGroup, err)
 	}
 
 	result, err := future.Result(client)
 	if err != nil {
-		return fmt.Errorf("getting the arm template of resource group %s: %w", meta.resourceGroup, err)
+		return nil, fmt.Errorf("getting the arm template of resource group %s: %w", resourceGroup, err)
 	}
 
 	// The response has been read into the ".Template" field as an interface, and the reader has been drained.
@@ -337,13 +741,14 @@ func (meta *MetaImpl) exportArmTemplate(ctx context.Context) error {
 	// to convert the ".Template" (interface{}) into that artificial type.
 	raw, err := json.Marshal(result.Template)
 	if err != nil {
-		return fmt.Errorf("marshalling the template: %w", err)
+		return nil, fmt.Errorf("marshalling the template: %w", err)
 	}
-	if err := json.Unmarshal(raw, &meta.armTemplate); err != nil {
-		return fmt.Errorf("unmarshalling the template: %w", err)
+	var tpl armtemplate.Template
+	if err := json.Unmarshal(raw, &tpl); err != nil {
+		return nil, fmt.Errorf("unmarshalling the template: %w", err)
 	}
 
-	return nil
+	return &tpl, nil
 }
 
 func (meta MetaImpl) stateToConfig(ctx context.Context, list ImportList) (ConfigInfos, error) {
@@ -375,20 +780,40 @@ func (meta MetaImpl) stateToConfig(ctx context.Context, list ImportList) (Config
 	return out, nil
 }
 
-func (meta MetaImpl) resolveDependency(configs ConfigInfos) (ConfigInfos, error) {
+// externalDependency is a dependency on an Azure resource that was never
+// itself imported (e.g. out of scope of the current run, or filtered out via
+// the resource mapping). In both modes it's recorded as a comment in
+// "depends_on"; in module mode (meta.modulePath != "") it's additionally
+// declared as a module variable, which the root configuration sets to the
+// concrete ID.
+type externalDependency struct {
+	varName string
+	id      string
+}
+
+func (meta MetaImpl) resolveDependency(configs ConfigInfos) (ConfigInfos, []externalDependency, error) {
+	// Without an ARM template (e.g. resources were discovered via a Resource
+	// Graph query or a management group/multi-RG scope), we have no cross
+	// resource dependency info to mine. Fall back to wiring only the implicit
+	// resource group dependency for each resource.
+	if meta.armTemplate == nil {
+		return meta.resolveDependencyFallback(configs), nil, nil
+	}
+
 	depInfo := meta.armTemplate.DependencyInfo()
 
 	configSet := map[armtemplate.ResourceId]ConfigInfo{}
 	for _, cfg := range configs {
 		armId, err := armtemplate.NewResourceId(cfg.ResourceID)
 		if err != nil {
-			return nil, fmt.Errorf("new arm tempalte resource id from azure resource id: %w", err)
+			return nil, nil, fmt.Errorf("new arm tempalte resource id from azure resource id: %w", err)
 		}
 		configSet[*armId] = cfg
 	}
 
 	// Iterate each config to add dependency by querying the dependency info from arm template.
 	var out ConfigInfos
+	var extDeps []externalDependency
 	for armId, cfg := range configSet {
 		if armId == armtemplate.ResourceGroupId {
 			out = append(out, cfg)
@@ -396,24 +821,79 @@ func (meta MetaImpl) resolveDependency(configs ConfigInfos) (ConfigInfos, error)
 		}
 		// This should never happen as we always ensure there is at least one implicit dependency on the resource group for each resource.
 		if _, ok := depInfo[armId]; !ok {
-			return nil, fmt.Errorf("can't find resource %q in the arm template", armId.ID(meta.subscriptionId, meta.resourceGroup))
+			return nil, nil, fmt.Errorf("can't find resource %q in the arm template", armId.ID(meta.subscriptionId, meta.resourceGroup))
 		}
 
-		if err := meta.hclBlockAppendDependency(cfg.hcl.Body().Blocks()[0].Body(), depInfo[armId], configSet); err != nil {
-			return nil, err
+		deps, err := meta.hclBlockAppendDependency(cfg.hcl.Body().Blocks()[0].Body(), depInfo[armId], configSet)
+		if err != nil {
+			return nil, nil, err
 		}
+		extDeps = append(extDeps, deps...)
 		out = append(out, cfg)
 	}
 
-	return out, nil
+	return out, extDeps, nil
 }
 
-func (meta MetaImpl) hclBlockAppendDependency(body *hclwrite.Body, armIds []armtemplate.ResourceId, cfgset map[armtemplate.ResourceId]ConfigInfo) error {
+// resolveDependencyFallback wires each imported resource to depend on the
+// resource group alone, which is the only dependency we can infer without an
+// ARM template to mine.
+func (meta MetaImpl) resolveDependencyFallback(configs ConfigInfos) ConfigInfos {
+	var rg *ConfigInfo
+	for i, cfg := range configs {
+		if cfg.ResourceID == armtemplate.ResourceGroupId.ID(meta.subscriptionId, meta.resourceGroup) {
+			rg = &configs[i]
+			break
+		}
+	}
+	if rg == nil {
+		return configs
+	}
+
+	for i, cfg := range configs {
+		if cfg.ResourceID == rg.ResourceID {
+			continue
+		}
+		body := configs[i].hcl.Body().Blocks()[0].Body()
+		src := []byte("depends_on = [\n" + rg.TFAddr.String() + ",\n]")
+		expr, diags := hclwrite.ParseConfig(src, "generate_depends_on", hcl.InitialPos)
+		if diags.HasErrors() {
+			continue
+		}
+		body.SetAttributeRaw("depends_on", expr.Body().GetAttribute("depends_on").Expr().BuildTokens(nil))
+	}
+
+	return configs
+}
+
+// hclBlockAppendDependency wires body's "depends_on" to the Terraform
+// addresses of armIds that were actually imported. An armId with no entry in
+// cfgset was out of scope of this run (e.g. filtered out, or outside the
+// resource group/subscription being imported); there's no resource address to
+// reference it by, so it's recorded as a comment. In module mode
+// (meta.modulePath != "") it's also returned as an externalDependency, from
+// which the caller declares a module variable and sets it from the root
+// configuration; "depends_on" itself still holds only the comment.
+func (meta MetaImpl) hclBlockAppendDependency(body *hclwrite.Body, armIds []armtemplate.ResourceId, cfgset map[armtemplate.ResourceId]ConfigInfo) ([]externalDependency, error) {
 	dependencies := []string{}
+	var extDeps []externalDependency
 	for _, armid := range armIds {
 		cfg, ok := cfgset[armid]
 		if !ok {
-			dependencies = append(dependencies, fmt.Sprintf("# Depending on %q, which is not imported by Terraform.", armid.ID(meta.subscriptionId, meta.resourceGroup)))
+			id := armid.ID(meta.subscriptionId, meta.resourceGroup)
+			if meta.modulePath == "" {
+				dependencies = append(dependencies, fmt.Sprintf("# Depending on %q, which is not imported by Terraform.", id))
+				continue
+			}
+			// depends_on only accepts resource/module/data source references,
+			// not a variable, so the dependency itself still has to stay a
+			// comment here - but the module is handed the ID via var.<name>
+			// from the root rather than a literal baked into its source, so
+			// the caller can point it at whatever resource actually has that
+			// ID without editing the module.
+			varName := externalDependencyVarName(id)
+			dependencies = append(dependencies, fmt.Sprintf("# Depending on %s (var.%s), which is not imported by Terraform.", id, varName))
+			extDeps = append(extDeps, externalDependency{varName: varName, id: id})
 			continue
 		}
 		dependencies = append(dependencies, cfg.TFAddr.String()+",")
@@ -422,16 +902,39 @@ func (meta MetaImpl) hclBlockAppendDependency(body *hclwrite.Body, armIds []armt
 		src := []byte("depends_on = [\n" + strings.Join(dependencies, "\n") + "\n]")
 		expr, diags := hclwrite.ParseConfig(src, "generate_depends_on", hcl.InitialPos)
 		if diags.HasErrors() {
-			return fmt.Errorf(`building "depends_on" attribute: %s`, diags.Error())
+			return nil, fmt.Errorf(`building "depends_on" attribute: %s`, diags.Error())
 		}
 
 		body.SetAttributeRaw("depends_on", expr.Body().GetAttribute("depends_on").Expr().BuildTokens(nil))
 	}
 
-	return nil
+	return extDeps, nil
 }
 
-func (meta MetaImpl) generateConfig(cfgs ConfigInfos) error {
+// externalDependencyVarName turns an Azure resource ID into a stable,
+// valid Terraform identifier to use as a module variable name.
+func externalDependencyVarName(id string) string {
+	var b strings.Builder
+	b.WriteString("ext_")
+	for _, r := range strings.ToLower(id) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+func (meta MetaImpl) generateConfig(cfgs ConfigInfos, extDeps []externalDependency) error {
+	if meta.modulePath != "" {
+		return meta.generateConfigAsModule(cfgs, extDeps)
+	}
+	if meta.mode == config.ModeAppend {
+		return meta.mergeConfig(cfgs)
+	}
+
 	cfgFile := filepath.Join(meta.outdir, "main.tf")
 	buf := bytes.NewBuffer([]byte{})
 	for i, cfg := range cfgs {
@@ -449,6 +952,94 @@ func (meta MetaImpl) generateConfig(cfgs ConfigInfos) error {
 	return nil
 }
 
+// mergeConfig appends cfgs' resource blocks onto outdir's existing main.tf
+// (creating one if absent) using hclwrite, so pre-existing blocks, comments
+// and formatting are preserved rather than clobbered by a flat rewrite.
+func (meta MetaImpl) mergeConfig(cfgs ConfigInfos) error {
+	cfgFile := filepath.Join(meta.outdir, "main.tf")
+
+	f := hclwrite.NewEmptyFile()
+	if b, err := os.ReadFile(cfgFile); err == nil {
+		parsed, diags := hclwrite.ParseConfig(b, cfgFile, hcl.InitialPos)
+		if diags.HasErrors() {
+			return fmt.Errorf("parsing existing %s: %s", cfgFile, diags.Error())
+		}
+		f = parsed
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("reading existing %s: %w", cfgFile, err)
+	}
+
+	for _, cfg := range cfgs {
+		f.Body().AppendNewline()
+		for _, block := range cfg.hcl.Body().Blocks() {
+			f.Body().AppendBlock(block)
+		}
+	}
+
+	if err := os.WriteFile(cfgFile, f.Bytes(), 0644); err != nil {
+		return fmt.Errorf("merging generated configuration into %s: %w", cfgFile, err)
+	}
+	return nil
+}
+
+// generateConfigAsModule writes cfgs under <outdir>/<modulePath>/ as a
+// reusable module (main.tf + outputs.tf exposing each imported resource's ID),
+// then writes a root main.tf wiring a "module" block to it. Any extDeps found
+// while resolving dependencies are hoisted to module variables, set from the
+// root module, so the module has no hardcoded references to resources outside
+// of it. This lets a team drop aztfy's output into an existing IaC repo as a
+// clean module boundary, rather than a flat main.tf.
+func (meta MetaImpl) generateConfigAsModule(cfgs ConfigInfos, extDeps []externalDependency) error {
+	moduleName := filepath.Base(meta.modulePath)
+	moduleDir := filepath.Join(meta.outdir, meta.modulePath)
+	if err := os.MkdirAll(moduleDir, 0755); err != nil {
+		return fmt.Errorf("creating module directory %q: %w", moduleDir, err)
+	}
+
+	mainBuf := bytes.NewBuffer([]byte{})
+	outputsBuf := bytes.NewBuffer([]byte{})
+	for i, cfg := range cfgs {
+		if _, err := cfg.DumpHCL(mainBuf); err != nil {
+			return err
+		}
+		if i != len(cfgs)-1 {
+			mainBuf.Write([]byte("\n"))
+		}
+		fmt.Fprintf(outputsBuf, "output %q {\n  value = %s.id\n}\n\n", cfg.TFAddr.Name+"_id", cfg.TFAddr.String())
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, "main.tf"), mainBuf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("generating module configuration file: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, "outputs.tf"), outputsBuf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("generating module outputs file: %w", err)
+	}
+
+	varsBuf := bytes.NewBuffer([]byte{})
+	rootBuf := bytes.NewBuffer([]byte{})
+	fmt.Fprintf(rootBuf, "module %q {\n  source = \"./%s\"\n", moduleName, meta.modulePath)
+	seen := map[string]bool{}
+	for _, d := range extDeps {
+		if seen[d.varName] {
+			continue
+		}
+		seen[d.varName] = true
+		fmt.Fprintf(varsBuf, "variable %q {\n  type = string\n}\n\n", d.varName)
+		fmt.Fprintf(rootBuf, "  %s = %q\n", d.varName, d.id)
+	}
+	rootBuf.Write([]byte("}\n"))
+
+	if varsBuf.Len() > 0 {
+		if err := os.WriteFile(filepath.Join(moduleDir, "variables.tf"), varsBuf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("generating module variables file: %w", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(meta.outdir, "main.tf"), rootBuf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("generating root configuration file: %w", err)
+	}
+
+	return nil
+}
+
 func (meta MetaImpl) cleanupTerraformAdd(tpl string) string {
 	segs := strings.Split(tpl, "\n")
 	// Removing:
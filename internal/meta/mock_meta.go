@@ -0,0 +1,133 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Azure/aztfy/internal/armtemplate"
+	"github.com/Azure/aztfy/internal/config"
+)
+
+// mockSubscriptionId is used to build resource IDs for MockMeta, which never
+// talks to Azure and so has no real subscription to anchor them to.
+const mockSubscriptionId = "00000000-0000-0000-0000-000000000000"
+
+// mockArmTemplateJSON is the default deterministic ARM template MockMeta
+// synthesizes when no replay template is configured. It's kept small but
+// non-trivial: two resources, so dependency resolution has something to do.
+const mockArmTemplateJSON = `{
+	"resources": [
+		{
+			"type": "Microsoft.Network/virtualNetworks",
+			"name": "mockvnet",
+			"apiVersion": "2021-02-01"
+		},
+		{
+			"type": "Microsoft.Storage/storageAccounts",
+			"name": "mockstorageacct",
+			"apiVersion": "2021-04-01"
+		}
+	]
+}`
+
+// MockMeta is a Meta implementation that requires no Azure credentials and no
+// terraform binary. Init synthesizes a deterministic armtemplate.Template (or,
+// if cfg.MockTemplateFile is set, replays a captured one, letting a user
+// reproduce a reported bug from its template JSON). Import succeeds unless a
+// failure has been injected for the item's TF address via InjectImportFailure.
+// GenerateCfg and ExportResourceMapping always succeed and write nothing; no
+// failure can be injected into them.
+type MockMeta struct {
+	resourceGroup    string
+	outdir           string
+	mockTemplateFile string
+	armTemplate      armtemplate.Template
+	injectedFailures map[string]error
+}
+
+func newMockMeta(cfg config.Config) (*MockMeta, error) {
+	return &MockMeta{
+		resourceGroup:    cfg.ResourceGroupName,
+		outdir:           cfg.OutputDir,
+		mockTemplateFile: cfg.MockTemplateFile,
+		injectedFailures: map[string]error{},
+	}, nil
+}
+
+func (m *MockMeta) ResourceGroupName() string {
+	return m.resourceGroup
+}
+
+func (m *MockMeta) Workspace() string {
+	return m.outdir
+}
+
+func (m *MockMeta) Init() error {
+	raw := []byte(mockArmTemplateJSON)
+	if m.mockTemplateFile != "" {
+		b, err := os.ReadFile(m.mockTemplateFile)
+		if err != nil {
+			return fmt.Errorf("reading mock template file %s: %w", m.mockTemplateFile, err)
+		}
+		raw = b
+	}
+	if err := json.Unmarshal(raw, &m.armTemplate); err != nil {
+		return fmt.Errorf("unmarshalling the mock template: %w", err)
+	}
+	return nil
+}
+
+func (m *MockMeta) ListResource() ImportList {
+	ids := make([]string, 0, len(m.armTemplate.Resources)+1)
+	for _, res := range m.armTemplate.Resources {
+		ids = append(ids, res.ID(mockSubscriptionId, m.resourceGroup))
+	}
+	ids = append(ids, armtemplate.ResourceGroupId.ID(mockSubscriptionId, m.resourceGroup))
+
+	l := make(ImportList, 0, len(ids))
+	for i, id := range ids {
+		l = append(l, ImportItem{
+			ResourceID: id,
+			TFAddr:     TFAddr{Name: fmt.Sprintf("res%d", i)},
+		})
+	}
+	return l
+}
+
+func (m *MockMeta) CleanTFState(addr string) {}
+
+// Import marks item imported, unless a failure has been injected for its TF
+// address via InjectImportFailure.
+func (m *MockMeta) Import(item *ImportItem) {
+	if err, ok := m.injectedFailures[item.TFAddr.String()]; ok {
+		item.ImportError = err
+		return
+	}
+	item.Imported = true
+}
+
+func (m *MockMeta) ImportAll(l ImportList, parallelism int) error {
+	for i := range l {
+		if l[i].Skip() {
+			continue
+		}
+		m.Import(&l[i])
+	}
+	return nil
+}
+
+func (m *MockMeta) GenerateCfg(l ImportList) error {
+	return nil
+}
+
+func (m *MockMeta) ExportResourceMapping(l ImportList) error {
+	return nil
+}
+
+// InjectImportFailure makes a subsequent Import of the resource at TF address
+// addr fail with err. It's for tests that exercise the import pipeline's
+// failure handling without needing a real Azure resource that actually fails.
+func (m *MockMeta) InjectImportFailure(addr string, err error) {
+	m.injectedFailures[addr] = err
+}
@@ -0,0 +1,81 @@
+package meta
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Azure/aztfy/internal/config"
+)
+
+// Compile-time assertion that MockMeta satisfies the same Meta interface the
+// Bubble Tea UI and command pipeline drive MetaImpl through.
+var _ Meta = &MockMeta{}
+
+func newTestMockMeta(t *testing.T) *MockMeta {
+	t.Helper()
+	m, err := newMockMeta(config.Config{ResourceGroupName: "rg1"})
+	if err != nil {
+		t.Fatalf("newMockMeta: %v", err)
+	}
+	if err := m.Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	return m
+}
+
+func TestMockMetaListResource(t *testing.T) {
+	m := newTestMockMeta(t)
+
+	l := m.ListResource()
+	// The two resources in mockArmTemplateJSON, plus the implicit resource group.
+	if got, want := len(l), 3; got != want {
+		t.Fatalf("got %d import items, want %d", got, want)
+	}
+	for _, item := range l {
+		if item.ResourceID == "" {
+			t.Errorf("item %s has an empty ResourceID", item.TFAddr)
+		}
+	}
+}
+
+func TestMockMetaImportAll(t *testing.T) {
+	m := newTestMockMeta(t)
+	l := m.ListResource()
+
+	if err := m.ImportAll(l, 0); err != nil {
+		t.Fatalf("ImportAll: %v", err)
+	}
+	for _, item := range l {
+		if !item.Imported {
+			t.Errorf("item %s was not imported", item.TFAddr)
+		}
+	}
+}
+
+func TestMockMetaInjectImportFailure(t *testing.T) {
+	m := newTestMockMeta(t)
+	l := m.ListResource()
+
+	failAddr := l[0].TFAddr.String()
+	wantErr := errors.New("injected failure")
+	m.InjectImportFailure(failAddr, wantErr)
+
+	if err := m.ImportAll(l, 0); err != nil {
+		t.Fatalf("ImportAll: %v", err)
+	}
+
+	for _, item := range l {
+		if item.TFAddr.String() != failAddr {
+			if !item.Imported {
+				t.Errorf("item %s was not imported", item.TFAddr)
+			}
+			continue
+		}
+		if item.Imported {
+			t.Errorf("item %s should not have been imported", item.TFAddr)
+		}
+		if item.ImportError != wantErr {
+			t.Errorf("item %s ImportError = %v, want %v", item.TFAddr, item.ImportError, wantErr)
+		}
+	}
+}
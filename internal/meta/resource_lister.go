@@ -0,0 +1,110 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/aztfy/internal/armtemplate"
+	"github.com/Azure/aztfy/internal/config"
+)
+
+// ResourceLister discovers the Azure resource IDs that are candidates for import.
+//
+// Some listers (e.g. the ARM template exporter) can additionally produce an
+// armtemplate.Template, which resolveDependency uses to infer cross-resource
+// dependencies. Listers that can't produce one (e.g. a Resource Graph query, or
+// a multi resource group/management group scope) return a nil template, and
+// dependency resolution falls back to wiring only the implicit resource group
+// dependency for each resource.
+type ResourceLister interface {
+	List(ctx context.Context) ([]string, *armtemplate.Template, error)
+}
+
+// newResourceLister selects the ResourceLister implementation based on how the
+// user asked aztfy to discover resources: a Resource Graph KQL query, a
+// management group/multi resource group scope, or (by default) exporting the
+// ARM template of a single resource group.
+func newResourceLister(cfg config.Config, auth *Authorizer, subscriptionId, resourceGroup string) (ResourceLister, error) {
+	switch {
+	case cfg.ResourceGraphQuery != "":
+		return &resourceGraphLister{
+			auth:           auth,
+			subscriptionId: subscriptionId,
+			query:          cfg.ResourceGraphQuery,
+		}, nil
+	case cfg.Scope != "":
+		return &scopeLister{
+			auth:           auth,
+			subscriptionId: subscriptionId,
+			scope:          cfg.Scope,
+		}, nil
+	default:
+		return &armTemplateLister{
+			auth:           auth,
+			subscriptionId: subscriptionId,
+			resourceGroup:  resourceGroup,
+		}, nil
+	}
+}
+
+// armTemplateLister discovers resources by exporting the ARM template of a
+// single resource group. This is the original (and default) discovery mode, and
+// is the sole lister that can populate an armtemplate.Template, since the other
+// scopes have no single resource group to export.
+type armTemplateLister struct {
+	auth           *Authorizer
+	subscriptionId string
+	resourceGroup  string
+}
+
+func (l *armTemplateLister) List(ctx context.Context) ([]string, *armtemplate.Template, error) {
+	tpl, err := exportArmTemplate(ctx, l.auth, l.resourceGroup)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ids []string
+	for _, res := range tpl.Resources {
+		ids = append(ids, res.ID(l.subscriptionId, l.resourceGroup))
+	}
+
+	return ids, tpl, nil
+}
+
+// resourceGraphLister discovers resources by running a Resource Graph KQL
+// query that returns resource IDs, potentially spanning subscriptions. There
+// is no single ARM template behind the result set, so dependency inference
+// falls back to the implicit resource group dependency per resource.
+type resourceGraphLister struct {
+	auth           *Authorizer
+	subscriptionId string
+	query          string
+}
+
+func (l *resourceGraphLister) List(ctx context.Context) ([]string, *armtemplate.Template, error) {
+	client := l.auth.NewResourceGraphClient()
+	ids, err := client.QueryResourceIDs(ctx, l.subscriptionId, l.query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying resource graph with %q: %w", l.query, err)
+	}
+	return ids, nil, nil
+}
+
+// scopeLister discovers resources across a management group or a set of
+// resource groups. Like resourceGraphLister, there is no single ARM template
+// available, so dependency inference falls back to the implicit resource group
+// dependency per resource.
+type scopeLister struct {
+	auth           *Authorizer
+	subscriptionId string
+	scope          string
+}
+
+func (l *scopeLister) List(ctx context.Context) ([]string, *armtemplate.Template, error) {
+	client := l.auth.NewScopeClient()
+	ids, err := client.ListResourceIDs(ctx, l.scope)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing resources under scope %q: %w", l.scope, err)
+	}
+	return ids, nil, nil
+}